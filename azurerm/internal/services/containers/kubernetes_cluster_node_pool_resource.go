@@ -0,0 +1,409 @@
+package containers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2019-06-01/containerservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/helpers/azure"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/helpers/tf"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/clients"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/timeouts"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/utils"
+)
+
+// nodePoolManagedByResourceTag is set on AgentPools created via `azurerm_kubernetes_cluster_node_pool`
+// so that `findDefaultNodePool` can tell them apart from the cluster's own default pool. This is
+// deliberately namespaced (rather than a generic key like `CreatedBy`) so it doesn't collide with a
+// tag a user has already applied to a pre-existing cluster's pools out-of-band.
+const nodePoolManagedByResourceTag = "CreatedByAzureRMKubernetesClusterNodePoolResource"
+const nodePoolManagedByResourceTagValue = "true"
+
+func resourceArmKubernetesClusterNodePool() *schema.Resource {
+	s := schemaNodePoolCommonFields()
+
+	s["kubernetes_cluster_id"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Required:     true,
+		ForceNew:     true,
+		ValidateFunc: azure.ValidateResourceID,
+	}
+
+	// standalone node pools default to `User` mode - unlike the default pool a cluster doesn't
+	// need this one to be `System`, since AKS only requires at least one `System` mode pool overall
+	s["mode"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Default:  string(containerservice.User),
+		ValidateFunc: validation.StringInSlice([]string{
+			string(containerservice.System),
+			string(containerservice.User),
+		}, false),
+	}
+
+	return &schema.Resource{
+		Create: resourceArmKubernetesClusterNodePoolCreate,
+		Read:   resourceArmKubernetesClusterNodePoolRead,
+		Update: resourceArmKubernetesClusterNodePoolUpdate,
+		Delete: resourceArmKubernetesClusterNodePoolDelete,
+
+		Importer: &schema.ResourceImporter{
+			State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+				if _, err := parseKubernetesNodePoolID(d.Id()); err != nil {
+					return []*schema.ResourceData{d}, err
+				}
+
+				return []*schema.ResourceData{d}, nil
+			},
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(60 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(60 * time.Minute),
+			Delete: schema.DefaultTimeout(60 * time.Minute),
+		},
+
+		Schema: s,
+	}
+}
+
+func resourceArmKubernetesClusterNodePoolCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Containers.AgentPoolsClient
+	ctx, cancel := timeouts.ForCreate(meta.(*clients.Client).StopContext, d)
+	defer cancel()
+
+	name := d.Get("name").(string)
+
+	clusterId, err := parseKubernetesClusterID(d.Get("kubernetes_cluster_id").(string))
+	if err != nil {
+		return err
+	}
+
+	existing, err := client.Get(ctx, clusterId.ResourceGroup, clusterId.ManagedClusterName, name)
+	if err != nil {
+		if !utils.ResponseWasNotFound(existing.Response) {
+			return fmt.Errorf("checking for presence of existing Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", name, clusterId.ManagedClusterName, clusterId.ResourceGroup, err)
+		}
+	}
+	if existing.ID != nil && *existing.ID != "" {
+		return tf.ImportAsExistsError("azurerm_kubernetes_cluster_node_pool", *existing.ID)
+	}
+
+	profile, err := expandKubernetesClusterNodePoolProfile(d)
+	if err != nil {
+		return err
+	}
+
+	parameters := containerservice.AgentPool{
+		Name: &name,
+		ManagedClusterAgentPoolProfileProperties: profile,
+	}
+
+	future, err := client.CreateOrUpdate(ctx, clusterId.ResourceGroup, clusterId.ManagedClusterName, name, parameters)
+	if err != nil {
+		return fmt.Errorf("creating/updating Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", name, clusterId.ManagedClusterName, clusterId.ResourceGroup, err)
+	}
+
+	if err := future.WaitForCompletionRef(ctx, client.Client); err != nil {
+		return fmt.Errorf("waiting for completion of Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", name, clusterId.ManagedClusterName, clusterId.ResourceGroup, err)
+	}
+
+	read, err := client.Get(ctx, clusterId.ResourceGroup, clusterId.ManagedClusterName, name)
+	if err != nil {
+		return fmt.Errorf("retrieving Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", name, clusterId.ManagedClusterName, clusterId.ResourceGroup, err)
+	}
+	if read.ID == nil {
+		return fmt.Errorf("Node Pool %q (Kubernetes Cluster %q / Resource Group %q) has a nil ID", name, clusterId.ManagedClusterName, clusterId.ResourceGroup)
+	}
+
+	d.SetId(*read.ID)
+
+	return resourceArmKubernetesClusterNodePoolRead(d, meta)
+}
+
+func resourceArmKubernetesClusterNodePoolUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Containers.AgentPoolsClient
+	ctx, cancel := timeouts.ForUpdate(meta.(*clients.Client).StopContext, d)
+	defer cancel()
+
+	id, err := parseKubernetesNodePoolID(d.Id())
+	if err != nil {
+		return err
+	}
+
+	existing, err := client.Get(ctx, id.ResourceGroup, id.ManagedClusterName, id.Name)
+	if err != nil {
+		return fmt.Errorf("retrieving Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", id.Name, id.ManagedClusterName, id.ResourceGroup, err)
+	}
+	if existing.ManagedClusterAgentPoolProfileProperties == nil {
+		return fmt.Errorf("retrieving Node Pool %q (Kubernetes Cluster %q / Resource Group %q): `properties` was nil", id.Name, id.ManagedClusterName, id.ResourceGroup)
+	}
+
+	profile, err := expandKubernetesClusterNodePoolProfile(d)
+	if err != nil {
+		return err
+	}
+	existing.ManagedClusterAgentPoolProfileProperties = profile
+
+	future, err := client.CreateOrUpdate(ctx, id.ResourceGroup, id.ManagedClusterName, id.Name, existing)
+	if err != nil {
+		return fmt.Errorf("updating Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", id.Name, id.ManagedClusterName, id.ResourceGroup, err)
+	}
+
+	if err := future.WaitForCompletionRef(ctx, client.Client); err != nil {
+		return fmt.Errorf("waiting for update of Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", id.Name, id.ManagedClusterName, id.ResourceGroup, err)
+	}
+
+	return resourceArmKubernetesClusterNodePoolRead(d, meta)
+}
+
+func resourceArmKubernetesClusterNodePoolRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Containers.AgentPoolsClient
+	ctx, cancel := timeouts.ForRead(meta.(*clients.Client).StopContext, d)
+	defer cancel()
+
+	id, err := parseKubernetesNodePoolID(d.Id())
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Get(ctx, id.ResourceGroup, id.ManagedClusterName, id.Name)
+	if err != nil {
+		if utils.ResponseWasNotFound(resp.Response) {
+			log.Printf("[DEBUG] Node Pool %q was not found (Kubernetes Cluster %q / Resource Group %q) - removing from state", id.Name, id.ManagedClusterName, id.ResourceGroup)
+			d.SetId("")
+			return nil
+		}
+
+		return fmt.Errorf("retrieving Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", id.Name, id.ManagedClusterName, id.ResourceGroup, err)
+	}
+
+	d.Set("name", id.Name)
+	d.Set("kubernetes_cluster_id", kubernetesClusterID{
+		SubscriptionID:     id.SubscriptionID,
+		ResourceGroup:      id.ResourceGroup,
+		ManagedClusterName: id.ManagedClusterName,
+	}.ID())
+
+	return flattenKubernetesClusterNodePoolProfile(d, resp.ManagedClusterAgentPoolProfileProperties)
+}
+
+func resourceArmKubernetesClusterNodePoolDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Containers.AgentPoolsClient
+	ctx, cancel := timeouts.ForDelete(meta.(*clients.Client).StopContext, d)
+	defer cancel()
+
+	id, err := parseKubernetesNodePoolID(d.Id())
+	if err != nil {
+		return err
+	}
+
+	future, err := client.Delete(ctx, id.ResourceGroup, id.ManagedClusterName, id.Name)
+	if err != nil {
+		return fmt.Errorf("deleting Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", id.Name, id.ManagedClusterName, id.ResourceGroup, err)
+	}
+
+	if err := future.WaitForCompletionRef(ctx, client.Client); err != nil {
+		return fmt.Errorf("waiting for deletion of Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", id.Name, id.ManagedClusterName, id.ResourceGroup, err)
+	}
+
+	return nil
+}
+
+func expandKubernetesClusterNodePoolProfile(d *schema.ResourceData) (*containerservice.ManagedClusterAgentPoolProfileProperties, error) {
+	autoscalingRaw := d.Get("autoscaling").([]interface{})
+	enableAutoScaling := len(autoscalingRaw) > 0
+
+	profile := containerservice.ManagedClusterAgentPoolProfileProperties{
+		EnableAutoScaling: utils.Bool(enableAutoScaling),
+		Mode:              containerservice.AgentPoolMode(d.Get("mode").(string)),
+		OsType:            containerservice.Linux,
+		Type:              containerservice.VirtualMachineScaleSets,
+		VMSize:            containerservice.VMSizeTypes(d.Get("vm_size").(string)),
+		Tags: map[string]*string{
+			nodePoolManagedByResourceTag: utils.String(nodePoolManagedByResourceTagValue),
+		},
+	}
+
+	availabilityZonesRaw := d.Get("availability_zones").([]interface{})
+	if availabilityZones := utils.ExpandStringSlice(availabilityZonesRaw); len(*availabilityZones) > 0 {
+		profile.AvailabilityZones = availabilityZones
+	}
+
+	if maxPods := int32(d.Get("max_pods").(int)); maxPods > 0 {
+		profile.MaxPods = utils.Int32(maxPods)
+	}
+
+	nodeTaintsRaw := d.Get("node_taints").([]interface{})
+	if nodeTaints := utils.ExpandStringSlice(nodeTaintsRaw); len(*nodeTaints) > 0 {
+		profile.NodeTaints = nodeTaints
+	}
+
+	if osDiskSizeGB := int32(d.Get("os_disk_size_gb").(int)); osDiskSizeGB > 0 {
+		profile.OsDiskSizeGB = utils.Int32(osDiskSizeGB)
+	}
+
+	if vnetSubnetID := d.Get("vnet_subnet_id").(string); vnetSubnetID != "" {
+		profile.VnetSubnetID = utils.String(vnetSubnetID)
+	}
+
+	count := d.Get("count").(int)
+
+	if enableAutoScaling {
+		autoscaling := autoscalingRaw[0].(map[string]interface{})
+		maxCount := autoscaling["max_count"].(int)
+		minCount := autoscaling["min_count"].(int)
+
+		if minCount > maxCount {
+			return nil, fmt.Errorf("`autoscaling.0.max_count` must be >= `autoscaling.0.min_count`")
+		}
+
+		profile.MaxCount = utils.Int32(int32(maxCount))
+		profile.MinCount = utils.Int32(int32(minCount))
+
+		if d.IsNewResource() {
+			profile.Count = utils.Int32(int32(count))
+		}
+	} else {
+		profile.EnableAutoScaling = utils.Bool(false)
+		profile.Count = utils.Int32(int32(count))
+	}
+
+	return &profile, nil
+}
+
+func flattenKubernetesClusterNodePoolProfile(d *schema.ResourceData, profile *containerservice.ManagedClusterAgentPoolProfileProperties) error {
+	if profile == nil {
+		return fmt.Errorf("`properties` was nil")
+	}
+
+	var availabilityZones []string
+	if profile.AvailabilityZones != nil {
+		availabilityZones = *profile.AvailabilityZones
+	}
+	d.Set("availability_zones", availabilityZones)
+
+	count := 0
+	if profile.Count != nil {
+		count = int(*profile.Count)
+	}
+	d.Set("count", count)
+
+	enableAutoScaling := false
+	if profile.EnableAutoScaling != nil {
+		enableAutoScaling = *profile.EnableAutoScaling
+	}
+
+	autoscaling := make([]interface{}, 0)
+	if enableAutoScaling {
+		maxCount := 0
+		if profile.MaxCount != nil {
+			maxCount = int(*profile.MaxCount)
+		}
+		minCount := 0
+		if profile.MinCount != nil {
+			minCount = int(*profile.MinCount)
+		}
+
+		autoscaling = append(autoscaling, map[string]interface{}{
+			"min_count": minCount,
+			"max_count": maxCount,
+		})
+	}
+	d.Set("autoscaling", autoscaling)
+
+	maxPods := 0
+	if profile.MaxPods != nil {
+		maxPods = int(*profile.MaxPods)
+	}
+	d.Set("max_pods", maxPods)
+
+	var nodeTaints []string
+	if profile.NodeTaints != nil {
+		nodeTaints = *profile.NodeTaints
+	}
+	d.Set("node_taints", nodeTaints)
+
+	osDiskSizeGB := 0
+	if profile.OsDiskSizeGB != nil {
+		osDiskSizeGB = int(*profile.OsDiskSizeGB)
+	}
+	d.Set("os_disk_size_gb", osDiskSizeGB)
+
+	vnetSubnetId := ""
+	if profile.VnetSubnetID != nil {
+		vnetSubnetId = *profile.VnetSubnetID
+	}
+	d.Set("vnet_subnet_id", vnetSubnetId)
+
+	d.Set("vm_size", string(profile.VMSize))
+	d.Set("mode", string(profile.Mode))
+
+	return nil
+}
+
+// kubernetesClusterID is the subset of a Managed Cluster's Resource ID needed to address its
+// AgentPools sub-resource.
+type kubernetesClusterID struct {
+	SubscriptionID     string
+	ResourceGroup      string
+	ManagedClusterName string
+}
+
+func (id kubernetesClusterID) ID() string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s", id.SubscriptionID, id.ResourceGroup, id.ManagedClusterName)
+}
+
+func parseKubernetesClusterID(input string) (*kubernetesClusterID, error) {
+	id, err := azure.ParseAzureResourceID(input)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Kubernetes Cluster ID %q: %+v", input, err)
+	}
+
+	clusterName, ok := id.Path["managedClusters"]
+	if !ok {
+		return nil, fmt.Errorf("parsing Kubernetes Cluster ID %q: `managedClusters` segment was not found", input)
+	}
+
+	return &kubernetesClusterID{
+		SubscriptionID:     id.SubscriptionID,
+		ResourceGroup:      id.ResourceGroup,
+		ManagedClusterName: clusterName,
+	}, nil
+}
+
+// kubernetesNodePoolID identifies a single AgentPool nested underneath a Managed Cluster.
+type kubernetesNodePoolID struct {
+	SubscriptionID     string
+	ResourceGroup      string
+	ManagedClusterName string
+	Name               string
+}
+
+func parseKubernetesNodePoolID(input string) (*kubernetesNodePoolID, error) {
+	id, err := azure.ParseAzureResourceID(input)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Kubernetes Cluster Node Pool ID %q: %+v", input, err)
+	}
+
+	clusterName, ok := id.Path["managedClusters"]
+	if !ok {
+		return nil, fmt.Errorf("parsing Kubernetes Cluster Node Pool ID %q: `managedClusters` segment was not found", input)
+	}
+
+	name, ok := id.Path["agentPools"]
+	if !ok {
+		return nil, fmt.Errorf("parsing Kubernetes Cluster Node Pool ID %q: `agentPools` segment was not found", input)
+	}
+
+	return &kubernetesNodePoolID{
+		SubscriptionID:     id.SubscriptionID,
+		ResourceGroup:      id.ResourceGroup,
+		ManagedClusterName: clusterName,
+		Name:               name,
+	}, nil
+}
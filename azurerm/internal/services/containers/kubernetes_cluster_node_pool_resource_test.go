@@ -0,0 +1,326 @@
+package containers_test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/acceptance"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/clients"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/utils"
+)
+
+func TestAccAzureRMKubernetesClusterNodePool_basic(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_kubernetes_cluster_node_pool", "test")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { acceptance.PreCheck(t) },
+		Providers:    acceptance.SupportedProviders,
+		CheckDestroy: testCheckAzureRMKubernetesClusterNodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_basic(data),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+			data.ImportStep(),
+		},
+	})
+}
+
+func TestAccAzureRMKubernetesClusterNodePool_requiresImport(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_kubernetes_cluster_node_pool", "test")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { acceptance.PreCheck(t) },
+		Providers:    acceptance.SupportedProviders,
+		CheckDestroy: testCheckAzureRMKubernetesClusterNodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_basic(data),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+			data.RequiresImportErrorStep(testAccAzureRMKubernetesClusterNodePool_requiresImport),
+		},
+	})
+}
+
+func TestAccAzureRMKubernetesClusterNodePool_autoScale(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_kubernetes_cluster_node_pool", "test")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { acceptance.PreCheck(t) },
+		Providers:    acceptance.SupportedProviders,
+		CheckDestroy: testCheckAzureRMKubernetesClusterNodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_autoScale(data),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+			data.ImportStep(),
+		},
+	})
+}
+
+func TestAccAzureRMKubernetesClusterNodePool_update(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_kubernetes_cluster_node_pool", "test")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { acceptance.PreCheck(t) },
+		Providers:    acceptance.SupportedProviders,
+		CheckDestroy: testCheckAzureRMKubernetesClusterNodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_basic(data),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+			data.ImportStep(),
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_update(data, 3),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+			data.ImportStep(),
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_update(data, 4),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+			data.ImportStep(),
+		},
+	})
+}
+
+func TestAccAzureRMKubernetesClusterNodePool_modeSystem(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_kubernetes_cluster_node_pool", "test")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { acceptance.PreCheck(t) },
+		Providers:    acceptance.SupportedProviders,
+		CheckDestroy: testCheckAzureRMKubernetesClusterNodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_mode(data, "System"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+					resource.TestCheckResourceAttr(data.ResourceName, "mode", "System"),
+				),
+			},
+			data.ImportStep(),
+		},
+	})
+}
+
+// TestAccAzureRMKubernetesClusterNodePool_defaultPoolModeUser asserts that `ValidateDefaultNodePoolMode`
+// allows switching the `default_node_pool` to `mode = "User"` once a `System` mode pool exists
+// elsewhere on the cluster (here, this standalone resource). On a from-scratch create Terraform can't
+// have provisioned this sibling resource before the cluster it depends on exists, so the only sequence
+// that can ever pass is: create the cluster with `default_node_pool.mode = "System"` alongside this
+// `System` mode pool, then update the existing cluster's `default_node_pool.mode` to `"User"`.
+func TestAccAzureRMKubernetesClusterNodePool_defaultPoolModeUser(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_kubernetes_cluster_node_pool", "test")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { acceptance.PreCheck(t) },
+		Providers:    acceptance.SupportedProviders,
+		CheckDestroy: testCheckAzureRMKubernetesClusterNodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_defaultPoolMode(data, "System"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+			{
+				Config: testAccAzureRMKubernetesClusterNodePool_defaultPoolMode(data, "User"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckAzureRMKubernetesClusterNodePoolExists(data.ResourceName),
+				),
+			},
+		},
+	})
+}
+
+// TestAccAzureRMKubernetesClusterNodePool_defaultPoolModeUserWithoutSystemPool asserts the reverse:
+// updating an *existing* cluster's `default_node_pool.mode` to `"User"` with no other `System` mode
+// pool on the cluster is rejected.
+func TestAccAzureRMKubernetesClusterNodePool_defaultPoolModeUserWithoutSystemPool(t *testing.T) {
+	data := acceptance.BuildTestData(t, "azurerm_kubernetes_cluster_node_pool", "test")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { acceptance.PreCheck(t) },
+		Providers:    acceptance.SupportedProviders,
+		CheckDestroy: testCheckAzureRMKubernetesClusterNodePoolDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAzureRMKubernetesCluster_basic(data),
+			},
+			{
+				Config:      testAccAzureRMKubernetesCluster_mode(data, "User"),
+				ExpectError: regexp.MustCompile("AKS requires at least one `System` mode pool per cluster"),
+			},
+		},
+	})
+}
+
+func testCheckAzureRMKubernetesClusterNodePoolExists(resourceName string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		client := acceptance.AzureProvider.Meta().(*clients.Client).Containers.AgentPoolsClient
+		ctx := acceptance.AzureProvider.Meta().(*clients.Client).StopContext
+
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("Node Pool not found: %s", resourceName)
+		}
+
+		clusterId, err := parseKubernetesClusterID(rs.Primary.Attributes["kubernetes_cluster_id"])
+		if err != nil {
+			return err
+		}
+
+		name := rs.Primary.Attributes["name"]
+		resp, err := client.Get(ctx, clusterId.ResourceGroup, clusterId.ManagedClusterName, name)
+		if err != nil {
+			if utils.ResponseWasNotFound(resp.Response) {
+				return fmt.Errorf("Node Pool %q (Kubernetes Cluster %q / Resource Group %q) does not exist", name, clusterId.ManagedClusterName, clusterId.ResourceGroup)
+			}
+			return fmt.Errorf("retrieving Node Pool %q (Kubernetes Cluster %q / Resource Group %q): %+v", name, clusterId.ManagedClusterName, clusterId.ResourceGroup, err)
+		}
+
+		return nil
+	}
+}
+
+func testCheckAzureRMKubernetesClusterNodePoolDestroy(s *terraform.State) error {
+	client := acceptance.AzureProvider.Meta().(*clients.Client).Containers.AgentPoolsClient
+	ctx := acceptance.AzureProvider.Meta().(*clients.Client).StopContext
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "azurerm_kubernetes_cluster_node_pool" {
+			continue
+		}
+
+		clusterId, err := parseKubernetesClusterID(rs.Primary.Attributes["kubernetes_cluster_id"])
+		if err != nil {
+			return err
+		}
+
+		name := rs.Primary.Attributes["name"]
+		resp, err := client.Get(ctx, clusterId.ResourceGroup, clusterId.ManagedClusterName, name)
+		if err != nil {
+			if utils.ResponseWasNotFound(resp.Response) {
+				continue
+			}
+			return err
+		}
+
+		return fmt.Errorf("Node Pool %q (Kubernetes Cluster %q / Resource Group %q) still exists", name, clusterId.ManagedClusterName, clusterId.ResourceGroup)
+	}
+
+	return nil
+}
+
+func testAccAzureRMKubernetesClusterNodePool_basic(data acceptance.TestData) string {
+	template := testAccAzureRMKubernetesCluster_basic(data)
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_kubernetes_cluster_node_pool" "test" {
+  name                  = "internal"
+  kubernetes_cluster_id = azurerm_kubernetes_cluster.test.id
+  vm_size               = "Standard_DS2_v2"
+  count                 = 1
+}
+`, template)
+}
+
+func testAccAzureRMKubernetesClusterNodePool_requiresImport(data acceptance.TestData) string {
+	template := testAccAzureRMKubernetesClusterNodePool_basic(data)
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_kubernetes_cluster_node_pool" "import" {
+  name                  = azurerm_kubernetes_cluster_node_pool.test.name
+  kubernetes_cluster_id = azurerm_kubernetes_cluster_node_pool.test.kubernetes_cluster_id
+  vm_size               = azurerm_kubernetes_cluster_node_pool.test.vm_size
+  count                 = azurerm_kubernetes_cluster_node_pool.test.count
+}
+`, template)
+}
+
+func testAccAzureRMKubernetesClusterNodePool_autoScale(data acceptance.TestData) string {
+	template := testAccAzureRMKubernetesCluster_basic(data)
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_kubernetes_cluster_node_pool" "test" {
+  name                  = "internal"
+  kubernetes_cluster_id = azurerm_kubernetes_cluster.test.id
+  vm_size               = "Standard_DS2_v2"
+
+  autoscaling {
+    min_count = 1
+    max_count = 3
+  }
+}
+`, template)
+}
+
+func testAccAzureRMKubernetesClusterNodePool_update(data acceptance.TestData, numberOfAgents int) string {
+	template := testAccAzureRMKubernetesCluster_basic(data)
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_kubernetes_cluster_node_pool" "test" {
+  name                  = "internal"
+  kubernetes_cluster_id = azurerm_kubernetes_cluster.test.id
+  vm_size               = "Standard_DS2_v2"
+  count                 = %d
+}
+`, template, numberOfAgents)
+}
+
+func testAccAzureRMKubernetesClusterNodePool_mode(data acceptance.TestData, mode string) string {
+	template := testAccAzureRMKubernetesCluster_basic(data)
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_kubernetes_cluster_node_pool" "test" {
+  name                  = "internal"
+  kubernetes_cluster_id = azurerm_kubernetes_cluster.test.id
+  vm_size               = "Standard_DS2_v2"
+  mode                  = %q
+  count                 = 1
+}
+`, template, mode)
+}
+
+// testAccAzureRMKubernetesClusterNodePool_defaultPoolMode creates the cluster with this standalone
+// `System` mode pool alongside a `default_node_pool` in the given `mode`, so that switching the
+// default pool's `mode` to `"User"` always has a `System` mode sibling already in state to satisfy
+// AKS's invariant.
+func testAccAzureRMKubernetesClusterNodePool_defaultPoolMode(data acceptance.TestData, defaultNodePoolMode string) string {
+	template := testAccAzureRMKubernetesCluster_mode(data, defaultNodePoolMode)
+	return fmt.Sprintf(`
+%s
+
+resource "azurerm_kubernetes_cluster_node_pool" "test" {
+  name                  = "system"
+  kubernetes_cluster_id = azurerm_kubernetes_cluster.test.id
+  vm_size               = "Standard_DS2_v2"
+  mode                  = "System"
+  count                 = 1
+}
+`, template)
+}
@@ -2,6 +2,9 @@ package containers
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2019-06-01/containerservice"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -9,123 +12,352 @@ import (
 	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/helpers/azure"
 	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/helpers/suppress"
 	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/helpers/validate"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/clients"
 	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/utils"
 )
 
+var maxSurgePercentageRegex = regexp.MustCompile(`^\d+%$`)
+
+// validateNodePoolMaxSurge accepts either a plain integer (e.g. `"1"`) or a percentage
+// (e.g. `"33%"`), matching what AKS's surge-upgrade API allows for `upgradeSettings.maxSurge`.
+func validateNodePoolMaxSurge(i interface{}, k string) (warnings []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %q to be string", k))
+		return warnings, errors
+	}
+
+	if maxSurgePercentageRegex.MatchString(v) {
+		return warnings, errors
+	}
+
+	if _, err := strconv.Atoi(strings.TrimSpace(v)); err != nil {
+		errors = append(errors, fmt.Errorf("%q must be an integer or a percentage (e.g. `1` or `33%%`), got %q", k, v))
+	}
+
+	return warnings, errors
+}
+
+// schemaNodePoolCommonFields returns the node pool atoms shared between the `default_node_pool`
+// block on `azurerm_kubernetes_cluster` and the standalone `azurerm_kubernetes_cluster_node_pool`
+// resource, so the two stay in lock-step as fields are added.
+func schemaNodePoolCommonFields() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"name": {
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			ValidateFunc: validate.KubernetesAgentPoolName,
+		},
+
+		"vm_size": {
+			Type:     schema.TypeString,
+			Required: true,
+			ForceNew: true,
+			// TODO: can we remove this?
+			DiffSuppressFunc: suppress.CaseDifference,
+			ValidateFunc:     validate.NoEmptyStrings,
+		},
+
+		"autoscaling": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"min_count": {
+						Type:         schema.TypeInt,
+						Required:     true,
+						ValidateFunc: validation.IntBetween(1, 100),
+					},
+
+					"max_count": {
+						Type:         schema.TypeInt,
+						Required:     true,
+						ValidateFunc: validation.IntBetween(1, 100),
+					},
+				},
+			},
+		},
+
+		"availability_zones": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+
+		"count": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      1,
+			ValidateFunc: validation.IntBetween(1, 100),
+		},
+
+		"max_pods": {
+			Type:     schema.TypeInt,
+			Optional: true,
+			Computed: true,
+			ForceNew: true,
+		},
+
+		"mode": {
+			Type:     schema.TypeString,
+			Optional: true,
+			Computed: true,
+			ValidateFunc: validation.StringInSlice([]string{
+				string(containerservice.System),
+				string(containerservice.User),
+			}, false),
+		},
+
+		"node_taints": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+
+		"os_disk_size_gb": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			ForceNew:     true,
+			Computed:     true,
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+
+		"vnet_subnet_id": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ForceNew:     true,
+			ValidateFunc: azure.ValidateResourceID,
+		},
+	}
+}
+
 func SchemaDefaultNodePool() *schema.Schema {
-	return &schema.Schema{
+	s := schemaNodePoolCommonFields()
+
+	s["type"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		ForceNew: true,
+		Default:  string(containerservice.VirtualMachineScaleSets),
+		ValidateFunc: validation.StringInSlice([]string{
+			string(containerservice.AvailabilitySet),
+			string(containerservice.VirtualMachineScaleSets),
+		}, false),
+	}
+
+	// Deprecated: the presence of the `autoscaling` block now determines whether autoscaling is enabled
+	s["enable_auto_scaling"] = &schema.Schema{
+		Type:       schema.TypeBool,
+		Optional:   true,
+		Computed:   true,
+		Deprecated: "Deprecated in favour of the `autoscaling` block - the presence of an `autoscaling` block now determines whether auto-scaling is enabled",
+	}
+
+	s["enable_node_public_ip"] = &schema.Schema{
+		Type:     schema.TypeBool,
+		Optional: true,
+	}
+
+	s["eviction_policy"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		ForceNew: true,
+		ValidateFunc: validation.StringInSlice([]string{
+			string(containerservice.Delete),
+			string(containerservice.Deallocate),
+		}, false),
+	}
+
+	// Deprecated: moved into the `autoscaling` block
+	s["max_count"] = &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		Computed:     true,
+		Deprecated:   "Deprecated in favour of `autoscaling.0.max_count`",
+		ValidateFunc: validation.IntBetween(1, 100),
+	}
+
+	// Deprecated: moved into the `autoscaling` block
+	s["min_count"] = &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		Computed:     true,
+		Deprecated:   "Deprecated in favour of `autoscaling.0.min_count`",
+		ValidateFunc: validation.IntBetween(1, 100),
+	}
+
+	// the default node pool defaults to `System` - AKS requires at least one `System` mode pool
+	// per cluster, so this is the safe default for the pool that's always present
+	s["mode"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Default:  string(containerservice.System),
+		ValidateFunc: validation.StringInSlice([]string{
+			string(containerservice.System),
+			string(containerservice.User),
+		}, false),
+	}
+
+	// NOTE: unlike `kubernetes_version` this is not ForceNew, since AKS supports upgrading an
+	// individual node pool's Kubernetes version independently of the control plane
+	s["orchestrator_version"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ValidateFunc: validate.NoEmptyStrings,
+	}
+
+	s["os_type"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		ForceNew: true,
+		Default:  string(containerservice.Linux),
+		ValidateFunc: validation.StringInSlice([]string{
+			string(containerservice.Linux),
+			string(containerservice.Windows),
+		}, false),
+	}
+
+	s["priority"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		ForceNew: true,
+		Default:  string(containerservice.Regular),
+		ValidateFunc: validation.StringInSlice([]string{
+			string(containerservice.Regular),
+			string(containerservice.Spot),
+		}, false),
+	}
+
+	s["spot_max_price"] = &schema.Schema{
+		Type:         schema.TypeFloat,
+		Optional:     true,
+		ForceNew:     true,
+		Default:      -1.0,
+		ValidateFunc: validation.FloatAtLeast(-1.0),
+	}
+
+	s["upgrade_settings"] = &schema.Schema{
 		Type:     schema.TypeList,
 		Optional: true,
 		MaxItems: 1,
 		Elem: &schema.Resource{
 			Schema: map[string]*schema.Schema{
-				// Required
-				"name": {
+				"max_surge": {
 					Type:         schema.TypeString,
 					Required:     true,
-					ForceNew:     true,
-					ValidateFunc: validate.KubernetesAgentPoolName,
+					ValidateFunc: validateNodePoolMaxSurge,
 				},
+			},
+		},
+	}
 
-				"type": {
-					Type:     schema.TypeString,
-					Optional: true,
-					ForceNew: true,
-					Default:  string(containerservice.VirtualMachineScaleSets),
-					ValidateFunc: validation.StringInSlice([]string{
-						string(containerservice.AvailabilitySet),
-						string(containerservice.VirtualMachineScaleSets),
-					}, false),
-				},
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: s,
+		},
+	}
+}
 
-				"vm_size": {
-					Type:     schema.TypeString,
-					Required: true,
-					ForceNew: true,
-					// TODO: can we remove this?
-					DiffSuppressFunc: suppress.CaseDifference,
-					ValidateFunc:     validate.NoEmptyStrings,
-				},
+// validateNodePoolOrchestratorVersionSkew enforces AKS's version-skew rule that a node pool's
+// Kubernetes version cannot be more than one minor version ahead of the control plane's.
+func validateNodePoolOrchestratorVersionSkew(orchestratorVersion, kubernetesVersion string) error {
+	nodePoolMinor, err := kubernetesMinorVersion(orchestratorVersion)
+	if err != nil {
+		return nil
+	}
 
-				// Optional
-				"availability_zones": {
-					Type:     schema.TypeList,
-					Optional: true,
-					Elem: &schema.Schema{
-						Type: schema.TypeString,
-					},
-				},
+	controlPlaneMinor, err := kubernetesMinorVersion(kubernetesVersion)
+	if err != nil {
+		return nil
+	}
 
-				"count": {
-					Type:         schema.TypeInt,
-					Optional:     true,
-					Default:      1,
-					ValidateFunc: validation.IntBetween(1, 100),
-				},
+	if nodePoolMinor-controlPlaneMinor > 1 {
+		return fmt.Errorf("`orchestrator_version` (%q) cannot be more than one minor version ahead of the control plane's `kubernetes_version` (%q)", orchestratorVersion, kubernetesVersion)
+	}
 
-				"enable_auto_scaling": {
-					Type:     schema.TypeBool,
-					Optional: true,
-				},
+	return nil
+}
 
-				"enable_node_public_ip": {
-					Type:     schema.TypeBool,
-					Optional: true,
-				},
+func kubernetesMinorVersion(version string) (int, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return 0, fmt.Errorf("parsing Kubernetes version %q", version)
+	}
 
-				"max_count": {
-					Type:         schema.TypeInt,
-					Optional:     true,
-					ValidateFunc: validation.IntBetween(1, 100),
-				},
+	return strconv.Atoi(parts[1])
+}
 
-				"max_pods": {
-					Type:     schema.TypeInt,
-					Optional: true,
-					Computed: true,
-					ForceNew: true,
-				},
+// clusterDataGetter is the subset of `*schema.ResourceData` and `*schema.ResourceDiff` that
+// `clusterHasSystemModeNodePool` needs, so the same lookup can be reused from both a resource's CRUD
+// functions and its `CustomizeDiff`.
+type clusterDataGetter interface {
+	Id() string
+	Get(key string) interface{}
+}
 
-				"min_count": {
-					Type:         schema.TypeInt,
-					Optional:     true,
-					ValidateFunc: validation.IntBetween(1, 100),
-				},
+// clusterHasSystemModeNodePool checks whether any *other* AgentPool on the cluster is already in
+// `System` mode, so the `default_node_pool` can safely be switched to `User` mode. Returns false
+// (rather than erroring) when the cluster doesn't exist yet, since on initial creation the default
+// node pool is the only pool AKS knows about.
+func clusterHasSystemModeNodePool(d clusterDataGetter, meta interface{}, defaultNodePoolName string) (bool, error) {
+	if d.Id() == "" {
+		return false, nil
+	}
 
-				"node_taints": {
-					Type:     schema.TypeList,
-					Optional: true,
-					Elem:     &schema.Schema{Type: schema.TypeString},
-				},
+	client := meta.(*clients.Client).Containers.AgentPoolsClient
+	ctx := meta.(*clients.Client).StopContext
 
-				"os_disk_size_gb": {
-					Type:         schema.TypeInt,
-					Optional:     true,
-					ForceNew:     true,
-					Computed:     true,
-					ValidateFunc: validation.IntAtLeast(1),
-				},
+	resourceGroup := d.Get("resource_group_name").(string)
+	clusterName := d.Get("name").(string)
 
-				"os_type": {
-					Type:     schema.TypeString,
-					Optional: true,
-					ForceNew: true,
-					Default:  string(containerservice.Linux),
-					ValidateFunc: validation.StringInSlice([]string{
-						string(containerservice.Linux),
-						string(containerservice.Windows),
-					}, false),
-				},
+	pools, err := client.List(ctx, resourceGroup, clusterName)
+	if err != nil {
+		return false, fmt.Errorf("listing Node Pools (Kubernetes Cluster %q / Resource Group %q): %+v", clusterName, resourceGroup, err)
+	}
 
-				"vnet_subnet_id": {
-					Type:         schema.TypeString,
-					Optional:     true,
-					ForceNew:     true,
-					ValidateFunc: azure.ValidateResourceID,
-				},
-			},
-		},
+	for _, pool := range pools.Values() {
+		if pool.Name != nil && *pool.Name == defaultNodePoolName {
+			continue
+		}
+		if pool.ManagedClusterAgentPoolProfileProperties != nil && pool.Mode == containerservice.System {
+			return true, nil
+		}
 	}
+
+	return false, nil
+}
+
+// ValidateDefaultNodePoolMode should be wired into the `azurerm_kubernetes_cluster` resource's
+// `CustomizeDiff` - unlike the rest of `default_node_pool`'s fields, this check needs live access to
+// the Azure API (to see whether a sibling `azurerm_kubernetes_cluster_node_pool` is `System` mode),
+// which `ExpandDefaultNodePool` doesn't have a `meta` to do. It rejects a plan that sets
+// `default_node_pool.0.mode` to `User` unless another node pool is already `System` mode, since AKS
+// requires at least one `System` mode pool per cluster. On initial create (`d.Id() == ""`) no other
+// node pool can exist yet, so `User` mode on the `default_node_pool` is always rejected there.
+func ValidateDefaultNodePoolMode(d *schema.ResourceDiff, meta interface{}) error {
+	mode := containerservice.AgentPoolMode(d.Get("default_node_pool.0.mode").(string))
+	if mode != containerservice.User {
+		return nil
+	}
+
+	name := d.Get("default_node_pool.0.name").(string)
+	systemPoolExists, err := clusterHasSystemModeNodePool(d, meta, name)
+	if err != nil {
+		return err
+	}
+	if !systemPoolExists {
+		return fmt.Errorf("`mode` cannot be set to `User` on the `default_node_pool` unless another node pool (e.g. an `azurerm_kubernetes_cluster_node_pool`) is in `System` mode - AKS requires at least one `System` mode pool per cluster")
+	}
+
+	return nil
 }
 
 func ExpandDefaultNodePool(d *schema.ResourceData) (*[]containerservice.ManagedClusterAgentPoolProfile, error) {
@@ -138,19 +370,79 @@ func ExpandDefaultNodePool(d *schema.ResourceData) (*[]containerservice.ManagedC
 
 	raw := input[0].(map[string]interface{})
 
-	enableAutoScaling := raw["enable_auto_scaling"].(bool)
+	autoscalingRaw := raw["autoscaling"].([]interface{})
+	enableAutoScaling := len(autoscalingRaw) > 0
+
+	// Deprecated: fall back to the legacy `enable_auto_scaling`/`min_count`/`max_count` fields when
+	// the `autoscaling` block hasn't been configured, so configs written before it was introduced
+	// don't have autoscaling silently switched off on their next apply
+	legacyMinCount := raw["min_count"].(int)
+	legacyMaxCount := raw["max_count"].(int)
+	if !enableAutoScaling && raw["enable_auto_scaling"].(bool) {
+		enableAutoScaling = true
+		autoscalingRaw = []interface{}{
+			map[string]interface{}{
+				"min_count": legacyMinCount,
+				"max_count": legacyMaxCount,
+			},
+		}
+	}
+
+	priority := containerservice.ScaleSetPriority(raw["priority"].(string))
+	evictionPolicy := raw["eviction_policy"].(string)
+	spotMaxPrice := raw["spot_max_price"].(float64)
+
+	if priority == containerservice.Spot {
+		if evictionPolicy == "" {
+			return nil, fmt.Errorf("`eviction_policy` must be set when `priority` is set to `Spot`")
+		}
+	} else {
+		if evictionPolicy != "" {
+			return nil, fmt.Errorf("`eviction_policy` can only be configured when `priority` is set to `Spot`")
+		}
+		if spotMaxPrice != -1.0 {
+			return nil, fmt.Errorf("`spot_max_price` can only be configured when `priority` is set to `Spot`")
+		}
+	}
+
+	// NOTE: whether `mode = "User"` is actually permitted here (i.e. whether a `System` mode pool
+	// exists elsewhere on the cluster) is enforced by `ValidateDefaultNodePoolMode` in the
+	// `azurerm_kubernetes_cluster` resource's `CustomizeDiff`, since that check needs live API access
+	// this function doesn't have.
+	mode := containerservice.AgentPoolMode(raw["mode"].(string))
+
 	profile := containerservice.ManagedClusterAgentPoolProfile{
 		EnableAutoScaling:  utils.Bool(enableAutoScaling),
 		EnableNodePublicIP: utils.Bool(raw["enable_node_public_ip"].(bool)),
+		Mode:               mode,
 		Name:               utils.String(raw["name"].(string)),
 		OsType:             containerservice.OSType(raw["os_type"].(string)),
+		ScaleSetPriority:   priority,
 		Type:               containerservice.AgentPoolType(raw["type"].(string)),
 		VMSize:             containerservice.VMSizeTypes(raw["vm_size"].(string)),
+	}
+
+	if priority == containerservice.Spot {
+		profile.ScaleSetEvictionPolicy = containerservice.ScaleSetEvictionPolicy(evictionPolicy)
+		profile.SpotMaxPrice = utils.Float64(spotMaxPrice)
+	}
+
+	if orchestratorVersion := raw["orchestrator_version"].(string); orchestratorVersion != "" {
+		if kubernetesVersion := d.Get("kubernetes_version").(string); kubernetesVersion != "" {
+			if err := validateNodePoolOrchestratorVersionSkew(orchestratorVersion, kubernetesVersion); err != nil {
+				return nil, err
+			}
+		}
 
-		//// TODO: support these in time
-		// OrchestratorVersion:    nil,
-		// ScaleSetEvictionPolicy: "",
-		// ScaleSetPriority:       "",
+		profile.OrchestratorVersion = utils.String(orchestratorVersion)
+	}
+
+	upgradeSettingsRaw := raw["upgrade_settings"].([]interface{})
+	if len(upgradeSettingsRaw) > 0 {
+		upgradeSettings := upgradeSettingsRaw[0].(map[string]interface{})
+		profile.UpgradeSettings = &containerservice.AgentPoolUpgradeSettings{
+			MaxSurge: utils.String(upgradeSettings["max_surge"].(string)),
+		}
 	}
 
 	availabilityZonesRaw := raw["availability_zones"].([]interface{})
@@ -177,8 +469,6 @@ func ExpandDefaultNodePool(d *schema.ResourceData) (*[]containerservice.ManagedC
 	}
 
 	count := raw["count"].(int)
-	maxCount := raw["max_count"].(int)
-	minCount := raw["min_count"].(int)
 
 	// Count must be set for the initial creation when using AutoScaling but cannot be updated
 	autoScaledCluster := enableAutoScaling && d.IsNewResource()
@@ -191,23 +481,24 @@ func ExpandDefaultNodePool(d *schema.ResourceData) (*[]containerservice.ManagedC
 	}
 
 	if enableAutoScaling {
-		if maxCount > 0 {
-			profile.MaxCount = utils.Int32(int32(maxCount))
-		} else {
-			return nil, fmt.Errorf("`max_count` must be configured when `enable_auto_scaling` is set to `true`")
-		}
-
-		if minCount > 0 {
-			profile.MinCount = utils.Int32(int32(minCount))
-		} else {
-			return nil, fmt.Errorf("`min_count` must be configured when `enable_auto_scaling` is set to `true`")
-		}
+		autoscaling := autoscalingRaw[0].(map[string]interface{})
+		maxCount := autoscaling["max_count"].(int)
+		minCount := autoscaling["min_count"].(int)
 
 		if minCount > maxCount {
-			return nil, fmt.Errorf("`max_count` must be >= `min_count`")
+			return nil, fmt.Errorf("`autoscaling.0.max_count` must be >= `autoscaling.0.min_count`")
 		}
-	} else if minCount > 0 || maxCount > 0 {
-		return nil, fmt.Errorf("`max_count` and `min_count` must be set to `0` when enable_auto_scaling is set to `false`")
+
+		profile.MaxCount = utils.Int32(int32(maxCount))
+		profile.MinCount = utils.Int32(int32(minCount))
+	} else {
+		// the `autoscaling` block has been removed (or was never configured) - explicitly tell AKS
+		// to turn autoscaling off and send an initialized `Count`, otherwise clearing the scaling
+		// stanza silently leaves the cluster in autoscale mode server-side
+		profile.EnableAutoScaling = utils.Bool(false)
+		profile.Count = utils.Int32(int32(count))
+		profile.MaxCount = nil
+		profile.MinCount = nil
 	}
 
 	return &[]containerservice.ManagedClusterAgentPoolProfile{
@@ -280,26 +571,76 @@ func FlattenDefaultNodePool(input *[]containerservice.ManagedClusterAgentPoolPro
 		vnetSubnetId = *agentPool.VnetSubnetID
 	}
 
+	spotMaxPrice := -1.0
+	if agentPool.SpotMaxPrice != nil {
+		spotMaxPrice = *agentPool.SpotMaxPrice
+	}
+
+	priority := string(agentPool.ScaleSetPriority)
+	if priority == "" {
+		priority = string(containerservice.Regular)
+	}
+
+	orchestratorVersion := ""
+	if agentPool.OrchestratorVersion != nil {
+		orchestratorVersion = *agentPool.OrchestratorVersion
+	}
+
+	upgradeSettings := make([]interface{}, 0)
+	if agentPool.UpgradeSettings != nil && agentPool.UpgradeSettings.MaxSurge != nil && *agentPool.UpgradeSettings.MaxSurge != "" {
+		upgradeSettings = append(upgradeSettings, map[string]interface{}{
+			"max_surge": *agentPool.UpgradeSettings.MaxSurge,
+		})
+	}
+
+	autoscaling := make([]interface{}, 0)
+	if enableAutoScaling {
+		autoscaling = append(autoscaling, map[string]interface{}{
+			"min_count": minCount,
+			"max_count": maxCount,
+		})
+	}
+
 	return &[]interface{}{
 		map[string]interface{}{
-			"availability_zones":    availabilityZones,
-			"count":                 count,
+			"autoscaling":        autoscaling,
+			"availability_zones": availabilityZones,
+			"count":              count,
+			// Deprecated: retained so existing state files continue to round-trip cleanly
 			"enable_auto_scaling":   enableAutoScaling,
 			"enable_node_public_ip": enableNodePublicIP,
+			"eviction_policy":       string(agentPool.ScaleSetEvictionPolicy),
 			"max_count":             maxCount,
 			"max_pods":              maxPods,
 			"min_count":             minCount,
+			"mode":                  string(agentPool.Mode),
 			"name":                  name,
 			"node_taints":           nodeTaints,
+			"orchestrator_version":  orchestratorVersion,
 			"os_disk_size_gb":       osDiskSizeGB,
 			"os_type":               string(agentPool.OsType),
+			"priority":              priority,
+			"spot_max_price":        spotMaxPrice,
 			"type":                  string(agentPool.Type),
+			"upgrade_settings":      upgradeSettings,
 			"vm_size":               string(agentPool.VMSize),
 			"vnet_subnet_id":        vnetSubnetId,
 		},
 	}, nil
 }
 
+// isNodePoolManagedByResource reports whether an AgentPool is owned by a standalone
+// `azurerm_kubernetes_cluster_node_pool` resource, in which case the `azurerm_kubernetes_cluster`
+// resource must not treat it as (or fight it for) the default node pool.
+func isNodePoolManagedByResource(input containerservice.ManagedClusterAgentPoolProfile) bool {
+	if input.Tags == nil {
+		return false
+	}
+
+	value, ok := input.Tags[nodePoolManagedByResourceTag]
+	return ok && value != nil && *value == nodePoolManagedByResourceTagValue
+}
+
 func findDefaultNodePool(input *[]containerservice.ManagedClusterAgentPoolProfile, d *schema.ResourceData) (*containerservice.ManagedClusterAgentPoolProfile, error) {
 	// first try loading this from the Resource Data if possible (e.g. when Created)
 	defaultNodePoolName := d.Get("default_node_pool.0.name")
@@ -308,15 +649,17 @@ func findDefaultNodePool(input *[]containerservice.ManagedClusterAgentPoolProfil
 	if defaultNodePoolName != "" {
 		// find it
 		for _, v := range *input {
-			if v.Name != nil && *v.Name == defaultNodePoolName {
+			if v.Name != nil && *v.Name == defaultNodePoolName && !isNodePoolManagedByResource(v) {
 				agentPool = &v
 				break
 			}
 		}
 	} else {
-		// otherwise we need to fall back to the name of the first agent pool
+		// otherwise fall back to the first `System` mode agent pool not owned by the standalone
+		// `azurerm_kubernetes_cluster_node_pool` resource - preferring `System` mode over "first by
+		// name" keeps import/drift-detection stable once `User` mode pools are added out-of-band
 		for _, v := range *input {
-			if v.Name == nil {
+			if v.Name == nil || isNodePoolManagedByResource(v) || v.Mode != containerservice.System {
 				continue
 			}
 
@@ -325,6 +668,20 @@ func findDefaultNodePool(input *[]containerservice.ManagedClusterAgentPoolProfil
 			break
 		}
 
+		// no `System` mode pool was found (e.g. older clusters that predate `mode`) - fall back to
+		// the first agent pool not owned by the standalone node-pool resource
+		if agentPool == nil {
+			for _, v := range *input {
+				if v.Name == nil || isNodePoolManagedByResource(v) {
+					continue
+				}
+
+				defaultNodePoolName = *v.Name
+				agentPool = &v
+				break
+			}
+		}
+
 		if defaultNodePoolName == nil {
 			return nil, fmt.Errorf("Unable to Determine Default Agent Pool")
 		}
@@ -0,0 +1,163 @@
+package containers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2019-06-01/containerservice"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/utils"
+)
+
+func TestIsNodePoolManagedByResource(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    containerservice.ManagedClusterAgentPoolProfile
+		expected bool
+	}{
+		{
+			name:     "no tags",
+			input:    containerservice.ManagedClusterAgentPoolProfile{},
+			expected: false,
+		},
+		{
+			name: "unrelated tags",
+			input: containerservice.ManagedClusterAgentPoolProfile{
+				Tags: map[string]*string{
+					"Environment": utils.String("Production"),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "a user's own `CreatedBy` tag happens to collide",
+			input: containerservice.ManagedClusterAgentPoolProfile{
+				Tags: map[string]*string{
+					"CreatedBy": utils.String("azurerm_kubernetes_cluster_node_pool"),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "managed by azurerm_kubernetes_cluster_node_pool",
+			input: containerservice.ManagedClusterAgentPoolProfile{
+				Tags: map[string]*string{
+					nodePoolManagedByResourceTag: utils.String(nodePoolManagedByResourceTagValue),
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "managed tag present with an unexpected value",
+			input: containerservice.ManagedClusterAgentPoolProfile{
+				Tags: map[string]*string{
+					nodePoolManagedByResourceTag: utils.String("false"),
+				},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := isNodePoolManagedByResource(tc.input)
+			if actual != tc.expected {
+				t.Fatalf("expected %t but got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateNodePoolMaxSurge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		hasError bool
+	}{
+		{
+			name:  "plain integer",
+			input: "1",
+		},
+		{
+			name:  "percentage",
+			input: "33%",
+		},
+		{
+			name:  "zero",
+			input: "0",
+		},
+		{
+			name:     "empty string",
+			input:    "",
+			hasError: true,
+		},
+		{
+			name:     "percentage missing digits",
+			input:    "%",
+			hasError: true,
+		},
+		{
+			name:     "not a number",
+			input:    "one",
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, errors := validateNodePoolMaxSurge(tc.input, "max_surge")
+			if tc.hasError && len(errors) == 0 {
+				t.Fatalf("expected an error for %q but got none", tc.input)
+			}
+			if !tc.hasError && len(errors) > 0 {
+				t.Fatalf("expected no error for %q but got %+v", tc.input, errors)
+			}
+		})
+	}
+}
+
+func TestValidateNodePoolOrchestratorVersionSkew(t *testing.T) {
+	testCases := []struct {
+		name                string
+		orchestratorVersion string
+		kubernetesVersion   string
+		hasError            bool
+	}{
+		{
+			name:                "same minor version",
+			orchestratorVersion: "1.18.0",
+			kubernetesVersion:   "1.18.2",
+		},
+		{
+			name:                "one minor version ahead",
+			orchestratorVersion: "1.19.0",
+			kubernetesVersion:   "1.18.2",
+		},
+		{
+			name:                "behind the control plane",
+			orchestratorVersion: "1.17.0",
+			kubernetesVersion:   "1.18.2",
+		},
+		{
+			name:                "two minor versions ahead",
+			orchestratorVersion: "1.20.0",
+			kubernetesVersion:   "1.18.2",
+			hasError:            true,
+		},
+		{
+			name:                "unparseable orchestrator version is left for the API to validate",
+			orchestratorVersion: "latest",
+			kubernetesVersion:   "1.18.2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateNodePoolOrchestratorVersionSkew(tc.orchestratorVersion, tc.kubernetesVersion)
+			if tc.hasError && err == nil {
+				t.Fatalf("expected an error but got none")
+			}
+			if !tc.hasError && err != nil {
+				t.Fatalf("expected no error but got %+v", err)
+			}
+		})
+	}
+}